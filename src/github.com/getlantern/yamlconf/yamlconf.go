@@ -0,0 +1,122 @@
+// Package yamlconf provides a Manager for working with configuration that's
+// stored on disk as yaml and that may change at runtime, either because it
+// was edited on disk or because the program updated it programmatically.
+package yamlconf
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/getlantern/golog"
+	"github.com/getlantern/yaml"
+)
+
+var log = golog.LoggerFor("yamlconf")
+
+// Config is the interface that application-specific configuration types
+// must implement in order to be managed by a Manager.
+type Config interface {
+	// GetVersion returns the version of this config, which Manager uses to
+	// detect concurrent modifications and to drive schema migrations.
+	GetVersion() int
+
+	// SetVersion sets the version of this config.
+	SetVersion(version int)
+
+	// ApplyDefaults populates any unset fields with their default values.
+	// It is called prior to every save.
+	ApplyDefaults()
+}
+
+// Manager manages a yaml-encoded Config of an application-specific type,
+// keeping an in-memory copy in sync with a file on disk.
+type Manager struct {
+	// FilePath is the path to the yaml file backing this Manager. It's only
+	// consulted when Store is nil, in which case Manager builds a default
+	// filesystem-backed ConfigStore from it.
+	FilePath string
+
+	// Store is the ConfigStore that this Manager reads and writes through.
+	// If nil, Manager uses NewFileStore(FilePath, BackupCount).
+	Store ConfigStore
+
+	// BackupCount is the number of rotating backups that the default
+	// filesystem ConfigStore keeps alongside FilePath. 0 (the default)
+	// disables backups. Only consulted when Store is nil.
+	BackupCount int
+
+	// Migrations maps a source schema version to the function that
+	// upgrades a Config at that version to the next one. When the on-disk
+	// config's version is older than EmptyConfig().GetVersion(), Manager
+	// applies these in order instead of treating the mismatch as an error.
+	Migrations map[int]func(prev Config) (Config, error)
+
+	// ObfuscationKey, if set, causes the on-disk file to be obfuscated using
+	// AES-OFB with this as the raw key. This provides no integrity
+	// protection; prefer Encryption for new deployments. Manager keeps
+	// reading ObfuscationKey-protected files for backwards compatibility.
+	ObfuscationKey []byte
+
+	// EmptyConfig constructs a new, empty instance of the Config managed by
+	// this Manager. It's used both to unmarshal yaml and to produce copies.
+	EmptyConfig func() Config
+
+	// Encryption, if set, enables authenticated encryption-at-rest for the
+	// config file, superseding ObfuscationKey for new writes. Existing
+	// ObfuscationKey-protected (or plaintext) files are still read
+	// transparently; see doReadFromDisk.
+	Encryption *Encryption
+
+	// mu guards cfg and fileInfo, which Watch's background goroutine reads
+	// and writes concurrently with whatever goroutine the caller uses to
+	// save, Restore or Upgrade the config.
+	mu       sync.Mutex
+	cfg      Config
+	fileInfo os.FileInfo
+}
+
+// setCfg records cfg as the current in-memory configuration.
+func (m *Manager) setCfg(cfg Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+}
+
+// currentCfg returns the current in-memory configuration.
+func (m *Manager) currentCfg() Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// currentFileInfo returns the os.FileInfo captured at the last successful
+// load or save.
+func (m *Manager) currentFileInfo() os.FileInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fileInfo
+}
+
+// setFileInfo records fileInfo as having been captured at the last
+// successful load or save.
+func (m *Manager) setFileInfo(fileInfo os.FileInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fileInfo = fileInfo
+}
+
+// copy returns a deep copy of cfg, obtained by round-tripping it through
+// yaml. This is used to compare configurations without aliasing the
+// caller's copy.
+func (m *Manager) copy(cfg Config) (Config, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal config for copying: %v", err)
+	}
+	copied := m.EmptyConfig()
+	if err := yaml.Unmarshal(b, copied); err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal config for copying: %v", err)
+	}
+	return copied, nil
+}
@@ -0,0 +1,269 @@
+package yamlconf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF identifies a key-derivation function used to turn a passphrase into
+// an encryption key.
+type KDF byte
+
+const (
+	// KDFNone means Encryption.Key is used as-is rather than derived.
+	KDFNone KDF = iota
+	// KDFScrypt derives the key from Encryption.Passphrase using scrypt.
+	KDFScrypt
+	// KDFArgon2id derives the key from Encryption.Passphrase using Argon2id.
+	KDFArgon2id
+)
+
+// AEADCipher identifies the authenticated cipher used to encrypt the config
+// payload.
+type AEADCipher byte
+
+const (
+	// CipherAESGCM encrypts with AES-256-GCM. This is the default.
+	CipherAESGCM AEADCipher = iota
+	// CipherChaCha20Poly1305 encrypts with ChaCha20-Poly1305, useful on
+	// platforms without AES hardware acceleration.
+	CipherChaCha20Poly1305
+)
+
+const (
+	keySize  = 32
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	argon2T  = 1
+	argon2M  = 64 * 1024
+	argon2P  = 4
+)
+
+// encryptionMagic identifies a file written by Encryption as opposed to the
+// legacy ObfuscationKey format or plain yaml. doReadFromDisk sniffs this to
+// decide how to decode a file without being told in advance.
+var encryptionMagic = []byte("YLC1")
+
+// Encryption configures authenticated encryption-at-rest for a Manager's
+// config file. Set either Key (a raw 32-byte key) or Passphrase (in which
+// case KDF defaults to KDFScrypt and a key is derived on demand). The
+// payload is encrypted with Cipher (defaults to CipherAESGCM) using a fresh
+// random nonce on every write.
+type Encryption struct {
+	Key        []byte
+	Passphrase string
+	KDF        KDF
+	Cipher     AEADCipher
+}
+
+// ErrTamperedConfig is returned when an encrypted config file can't be
+// decrypted and authenticated on read, meaning it was corrupted or modified
+// after it was written, or that Manager.Encryption doesn't hold the Key or
+// Passphrase it was written with (a Key that isn't 32 bytes included).
+// Unlike a parse error, retrying or re-reading won't help; callers
+// should refuse to boot, alert, or fall back to a backup.
+type ErrTamperedConfig struct {
+	Path  string
+	cause error
+}
+
+func (e *ErrTamperedConfig) Error() string {
+	return fmt.Sprintf("config at %s failed authentication and may have been tampered with: %v", e.Path, e.cause)
+}
+
+// effectiveKDF returns the KDF to derive a passphrase-based key with,
+// defaulting to KDFScrypt when the caller didn't pick one. This default must
+// only be consulted when encrypting a fresh payload: decryptPayload's probe
+// already carries the literal on-disk KDF ID, and re-applying this default
+// there would make a file written with a raw Key (on-disk KDFNone) get
+// probed with KDFScrypt whenever the reading Manager also has a Passphrase
+// configured, failing authentication on an otherwise untampered file.
+func (e *Encryption) effectiveKDF() KDF {
+	if e.KDF == KDFNone && e.Passphrase != "" {
+		return KDFScrypt
+	}
+	return e.KDF
+}
+
+func (e *Encryption) aeadCipher() AEADCipher {
+	return e.Cipher
+}
+
+// keyFor derives the encryption key for the given salt using e.KDF exactly
+// as set, with no defaulting. Whether to use the raw Key or derive one from
+// Passphrase is decided by e.KDF rather than by whether Passphrase happens
+// to be set: decryptPayload's probe carries the literal on-disk KDF ID, and
+// that's the only thing that can say for certain how the file was written.
+// The derivation is deliberately not memoized: Encryption is shared across
+// concurrent writers (e.g. an application save racing Manager.Watch's
+// migration path), and caching the result on the Encryption value itself
+// would mean mutating shared state without a lock. Argon2id/scrypt are slow
+// by design, but that cost is the same order as the write they're guarding,
+// so re-deriving on every call keeps Encryption safe for concurrent use
+// without needing one.
+func (e *Encryption) keyFor(salt []byte) ([]byte, error) {
+	if e.KDF == KDFNone {
+		if len(e.Key) != keySize {
+			return nil, fmt.Errorf("Encryption.Key must be %d bytes, got %d", keySize, len(e.Key))
+		}
+		return e.Key, nil
+	}
+
+	switch e.KDF {
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(e.Passphrase), salt, argon2T, argon2M, argon2P, keySize), nil
+	case KDFScrypt:
+		key, err := scrypt.Key([]byte(e.Passphrase), salt, scryptN, scryptR, scryptP, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to derive key with scrypt: %v", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("Unsupported KDF %d for passphrase-based encryption", e.KDF)
+	}
+}
+
+func (e *Encryption) aead(key []byte) (cipher.AEAD, error) {
+	switch e.aeadCipher() {
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to initialize AES: %v", err)
+		}
+		return cipher.NewGCM(block)
+	}
+}
+
+// encryptPayload encrypts plaintext per m.Encryption and returns a
+// self-describing file: magic, cipher ID, KDF ID, salt (if any), nonce, and
+// ciphertext.
+func (m *Manager) encryptPayload(plaintext []byte) ([]byte, error) {
+	enc := m.Encryption
+
+	salt := []byte{}
+	if enc.Passphrase != "" {
+		salt = make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("Unable to generate salt: %v", err)
+		}
+	}
+
+	resolved := &Encryption{Key: enc.Key, Passphrase: enc.Passphrase, KDF: enc.effectiveKDF(), Cipher: enc.Cipher}
+
+	key, err := resolved.keyFor(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := resolved.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("Unable to generate nonce: %v", err)
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+
+	var out bytes.Buffer
+	out.Write(encryptionMagic)
+	out.WriteByte(byte(resolved.aeadCipher()))
+	out.WriteByte(byte(resolved.KDF))
+	if resolved.KDF != KDFNone {
+		out.WriteByte(byte(len(salt)))
+		out.Write(salt)
+	}
+	_ = binary.Write(&out, binary.BigEndian, uint16(len(nonce)))
+	out.Write(nonce)
+	out.Write(ciphertext)
+
+	return out.Bytes(), nil
+}
+
+// decryptPayload parses the self-describing header written by
+// encryptPayload and returns the authenticated plaintext. It returns an
+// error if raw is malformed or if authentication fails.
+func (m *Manager) decryptPayload(raw []byte) ([]byte, error) {
+	enc := m.Encryption
+	r := bytes.NewReader(raw)
+
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, encryptionMagic) {
+		return nil, fmt.Errorf("Missing or invalid encryption header")
+	}
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("Truncated encryption header: %v", err)
+	}
+	cipherID := AEADCipher(header[0])
+	kdfID := KDF(header[1])
+
+	var salt []byte
+	if kdfID != KDFNone {
+		saltLen := make([]byte, 1)
+		if _, err := io.ReadFull(r, saltLen); err != nil {
+			return nil, fmt.Errorf("Truncated salt length: %v", err)
+		}
+		salt = make([]byte, saltLen[0])
+		if _, err := io.ReadFull(r, salt); err != nil {
+			return nil, fmt.Errorf("Truncated salt: %v", err)
+		}
+	}
+
+	var nonceLen uint16
+	if err := binary.Read(r, binary.BigEndian, &nonceLen); err != nil {
+		return nil, fmt.Errorf("Truncated nonce length: %v", err)
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("Truncated nonce: %v", err)
+	}
+
+	ciphertext, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read ciphertext: %v", err)
+	}
+
+	// Use the on-disk cipher/KDF IDs to decrypt rather than whatever the
+	// Manager is currently configured to write with, so that rotating
+	// Cipher or KDF doesn't break reading older files.
+	probe := &Encryption{Key: enc.Key, Passphrase: enc.Passphrase, KDF: kdfID, Cipher: cipherID}
+
+	key, err := probe.keyFor(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := probe.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Authentication failed: %v", err)
+	}
+
+	return plaintext, nil
+}
+
+func hasEncryptionHeader(raw []byte) bool {
+	return len(raw) >= len(encryptionMagic) && bytes.Equal(raw[:len(encryptionMagic)], encryptionMagic)
+}
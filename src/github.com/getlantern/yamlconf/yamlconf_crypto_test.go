@@ -0,0 +1,105 @@
+package yamlconf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testEncryptionManager(enc *Encryption) *Manager {
+	return &Manager{FilePath: "test.yaml", Encryption: enc}
+}
+
+func roundTrip(t *testing.T, m *Manager, plaintext []byte) {
+	t.Helper()
+
+	ciphertext, err := m.encryptPayload(plaintext)
+	if err != nil {
+		t.Fatalf("encryptPayload: %v", err)
+	}
+	if !hasEncryptionHeader(ciphertext) {
+		t.Fatalf("expected encrypted output to carry the encryption header")
+	}
+
+	decrypted, err := m.decryptPayload(ciphertext)
+	if err != nil {
+		t.Fatalf("decryptPayload: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("roundtrip mismatch: got %q, want %q", decrypted, plaintext)
+	}
+}
+
+func TestEncryptDecryptRoundTripRawKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keySize)
+	m := testEncryptionManager(&Encryption{Key: key})
+	roundTrip(t, m, []byte("hello: world\n"))
+}
+
+func TestEncryptDecryptRoundTripPassphraseScrypt(t *testing.T) {
+	m := testEncryptionManager(&Encryption{Passphrase: "correct horse battery staple"})
+	roundTrip(t, m, []byte("hello: world\n"))
+}
+
+func TestEncryptDecryptRoundTripPassphraseArgon2id(t *testing.T) {
+	m := testEncryptionManager(&Encryption{Passphrase: "correct horse battery staple", KDF: KDFArgon2id})
+	roundTrip(t, m, []byte("hello: world\n"))
+}
+
+func TestEncryptDecryptRoundTripChaCha20Poly1305(t *testing.T) {
+	key := bytes.Repeat([]byte{0x07}, keySize)
+	m := testEncryptionManager(&Encryption{Key: key, Cipher: CipherChaCha20Poly1305})
+	roundTrip(t, m, []byte("hello: world\n"))
+}
+
+func TestDecryptRawKeyFileWithPassphraseConfiguredManager(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keySize)
+	writer := testEncryptionManager(&Encryption{Key: key})
+
+	ciphertext, err := writer.encryptPayload([]byte("hello: world\n"))
+	if err != nil {
+		t.Fatalf("encryptPayload: %v", err)
+	}
+
+	reader := testEncryptionManager(&Encryption{Key: key, Passphrase: "unrelated passphrase"})
+	decrypted, err := reader.decryptPayload(ciphertext)
+	if err != nil {
+		t.Fatalf("decryptPayload: %v (file written with a raw Key must still decrypt even if the reading Manager also has a Passphrase set)", err)
+	}
+	if !bytes.Equal(decrypted, []byte("hello: world\n")) {
+		t.Fatalf("roundtrip mismatch: got %q", decrypted)
+	}
+}
+
+func TestDecodeDetectsTamperedConfig(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keySize)
+	m := testEncryptionManager(&Encryption{Key: key})
+
+	ciphertext, err := m.encryptPayload([]byte("hello: world\n"))
+	if err != nil {
+		t.Fatalf("encryptPayload: %v", err)
+	}
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	_, err = m.decode(tampered, false)
+	if err == nil {
+		t.Fatalf("expected tampering to be detected")
+	}
+	if _, ok := err.(*ErrTamperedConfig); !ok {
+		t.Fatalf("expected *ErrTamperedConfig, got %T: %v", err, err)
+	}
+}
+
+func TestDecodeFallsBackToPlaintextWithoutEncryption(t *testing.T) {
+	m := testEncryptionManager(nil)
+	plaintext := []byte("hello: world\n")
+
+	decoded, err := m.decode(plaintext, false)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !bytes.Equal(decoded, plaintext) {
+		t.Fatalf("expected passthrough for unencrypted input, got %q", decoded)
+	}
+}
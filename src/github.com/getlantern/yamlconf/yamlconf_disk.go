@@ -4,9 +4,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"os"
 	"reflect"
 
 	"github.com/getlantern/yaml"
@@ -18,11 +16,11 @@ func (m *Manager) loadFromDisk() error {
 }
 
 func (m *Manager) reloadFromDisk() (bool, error) {
-	fileInfo, err := os.Stat(m.FilePath)
+	fileInfo, err := m.store().Stat()
 	if err != nil {
 		return false, fmt.Errorf("Unable to stat config file %s: %s", m.FilePath, err)
 	}
-	if m.fileInfo == fileInfo {
+	if m.currentFileInfo() == fileInfo {
 		log.Trace("Config unchanged on disk")
 		return false, nil
 	}
@@ -47,15 +45,28 @@ func (m *Manager) reloadFromDisk() (bool, error) {
 		}
 	}
 
-	if m.cfg != nil && m.cfg.GetVersion() != cfg.GetVersion() {
+	if len(m.Migrations) > 0 && cfg.GetVersion() < m.EmptyConfig().GetVersion() {
+		log.Debugf("Config on disk is version %d, migrating to %d", cfg.GetVersion(), m.EmptyConfig().GetVersion())
+		migrated, _, err := m.migrate(cfg)
+		if err != nil {
+			return false, fmt.Errorf("Unable to migrate config on disk: %v", err)
+		}
+		if err := m.writeToDisk(migrated); err != nil {
+			return false, fmt.Errorf("Unable to write migrated config to disk: %v", err)
+		}
+		if refreshed, statErr := m.store().Stat(); statErr == nil {
+			fileInfo = refreshed
+		}
+		cfg = migrated
+	} else if current := m.currentCfg(); current != nil && current.GetVersion() != cfg.GetVersion() {
 		log.Trace("Version mismatch on disk, overwriting what's on disk with current version")
-		if err := m.writeToDisk(m.cfg); err != nil {
+		if err := m.writeToDisk(current); err != nil {
 			log.Errorf("Unable to write to disk: %v", err)
 		}
-		return false, fmt.Errorf("Version of config on disk did not match expected. Expected %d, found %d", m.cfg.GetVersion(), cfg.GetVersion())
+		return false, fmt.Errorf("Version of config on disk did not match expected. Expected %d, found %d", current.GetVersion(), cfg.GetVersion())
 	}
 
-	if reflect.DeepEqual(m.cfg, cfg) {
+	if reflect.DeepEqual(m.currentCfg(), cfg) {
 		log.Trace("Config on disk is same as in memory, ignoring")
 		return false, nil
 	}
@@ -63,35 +74,30 @@ func (m *Manager) reloadFromDisk() (bool, error) {
 	log.Debugf("Configuration changed on disk, applying")
 
 	m.setCfg(cfg)
-	m.fileInfo = fileInfo
+	m.setFileInfo(fileInfo)
 
 	return true, nil
 }
 
 func (m *Manager) doReadFromDisk(allowObfuscation bool) (Config, error) {
-	infile, err := os.Open(m.FilePath)
+	infile, err := m.store().Open()
 	if err != nil {
 		return nil, fmt.Errorf("Unable to open config file %v for reading: %v", m.FilePath, err)
 	}
 	defer infile.Close()
 
-	var in io.Reader = infile
-	if allowObfuscation && m.ObfuscationKey != nil {
-		// Read file as obfuscated with AES
-		stream, err := m.obfuscationStream()
-		if err != nil {
-			return nil, err
-		}
-		in = &cipher.StreamReader{S: stream, R: in}
+	raw, err := ioutil.ReadAll(infile)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config from %s: %s", m.FilePath, err)
 	}
 
-	bytes, err := ioutil.ReadAll(in)
+	plaintext, err := m.decode(raw, allowObfuscation)
 	if err != nil {
-		return nil, fmt.Errorf("Error reading config from %s: %s", m.FilePath, err)
+		return nil, err
 	}
 
 	cfg := m.EmptyConfig()
-	err = yaml.Unmarshal(bytes, cfg)
+	err = yaml.Unmarshal(plaintext, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("Error unmarshaling config yaml from %s: %s", m.FilePath, err)
 	}
@@ -99,24 +105,55 @@ func (m *Manager) doReadFromDisk(allowObfuscation bool) (Config, error) {
 	return cfg, nil
 }
 
+// decode turns the raw bytes read from disk into plaintext yaml, dispatching
+// on the format actually found on disk rather than on how Manager is
+// currently configured. This lets Manager.Encryption be added, or its Cipher
+// or KDF changed, without breaking the next read of a file written under the
+// old settings. An encrypted file still needs Manager.Encryption to be set,
+// and an obfuscated one still needs Manager.ObfuscationKey.
+func (m *Manager) decode(raw []byte, allowObfuscation bool) ([]byte, error) {
+	if hasEncryptionHeader(raw) {
+		if m.Encryption == nil {
+			return nil, fmt.Errorf("Config at %s is encrypted but Manager.Encryption is not set", m.FilePath)
+		}
+		plaintext, err := m.decryptPayload(raw)
+		if err != nil {
+			return nil, &ErrTamperedConfig{Path: m.FilePath, cause: err}
+		}
+		return plaintext, nil
+	}
+
+	if allowObfuscation && m.ObfuscationKey != nil {
+		// Read file as obfuscated with AES
+		stream, err := m.obfuscationStream()
+		if err != nil {
+			return nil, err
+		}
+		plaintext := make([]byte, len(raw))
+		stream.XORKeyStream(plaintext, raw)
+		return plaintext, nil
+	}
+
+	return raw, nil
+}
+
 func (m *Manager) saveToDiskAndUpdate(updated Config) (bool, error) {
 	log.Trace("Applying defaults before saving")
 	updated.ApplyDefaults()
 
 	log.Trace("Remembering current version")
-	original := m.cfg
+	original := m.currentCfg()
 	nextVersion := 0
 	if original != nil {
 		log.Trace("Copying original config in preparation for comparison")
 		var err error
-		original, err = m.copy(m.cfg)
+		nextVersion = original.GetVersion() + 1
+		original, err = m.copy(original)
 		if err != nil {
 			return false, fmt.Errorf("Unable to copy original config for comparison")
 		}
 		log.Trace("Set version to 0 prior to comparison")
 		original.SetVersion(0)
-		log.Trace("Incrementing version")
-		nextVersion = m.cfg.GetVersion() + 1
 	}
 
 	log.Trace("Compare config without version")
@@ -142,45 +179,81 @@ func (m *Manager) saveToDiskAndUpdate(updated Config) (bool, error) {
 }
 
 func (m *Manager) writeToDisk(cfg Config) error {
-	bytes, err := yaml.Marshal(cfg)
+	plaintext, err := yaml.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("Unable to marshal config yaml: %s", err)
 	}
 
-	outfile, err := os.OpenFile(m.FilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	out, err := m.encode(plaintext)
 	if err != nil {
-		return fmt.Errorf("Unable to open file %v for writing: %v", m.FilePath, err)
+		return fmt.Errorf("Unable to encode config for %s: %v", m.FilePath, err)
+	}
+
+	if err := m.store().WriteAtomic(out, cfg.GetVersion()); err != nil {
+		return fmt.Errorf("Unable to write config to %s: %v", m.FilePath, err)
+	}
+
+	fileInfo, err := m.store().Stat()
+	if err != nil {
+		return fmt.Errorf("Unable to stat file %s: %s", m.FilePath, err)
+	}
+	m.setFileInfo(fileInfo)
+	return nil
+}
+
+// encode turns plaintext yaml into the bytes that should actually land on
+// disk, preferring Manager.Encryption (authenticated) over the legacy
+// ObfuscationKey (unauthenticated) when both are set.
+func (m *Manager) encode(plaintext []byte) ([]byte, error) {
+	if m.Encryption != nil {
+		return m.encryptPayload(plaintext)
 	}
-	defer outfile.Close()
 
-	var out io.Writer = outfile
 	if m.ObfuscationKey != nil {
-		// write file as obfuscated with AES
 		stream, err := m.obfuscationStream()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		out = &cipher.StreamWriter{S: stream, W: out}
+		out := make([]byte, len(plaintext))
+		stream.XORKeyStream(out, plaintext)
+		return out, nil
 	}
-	_, err = out.Write(bytes)
-	if err != nil {
-		return fmt.Errorf("Unable to write config yaml to file %s: %s", m.FilePath, err)
+
+	return plaintext, nil
+}
+
+// Restore reloads the nth most recent backup (1 being the most recent) as
+// the current config, writing it back out through the normal atomic-write
+// path so that the bad version it's replacing doesn't linger as the only
+// copy on disk. It returns an error if the Manager's ConfigStore doesn't
+// keep backups.
+func (m *Manager) Restore(n int) error {
+	bs, ok := m.store().(BackupStore)
+	if !ok {
+		return fmt.Errorf("Configured ConfigStore does not support Restore")
 	}
-	m.fileInfo, err = os.Stat(m.FilePath)
+
+	raw, err := bs.Restore(n)
 	if err != nil {
-		return fmt.Errorf("Unable to stat file %s: %s", m.FilePath, err)
+		return fmt.Errorf("Unable to read backup #%d: %v", n, err)
 	}
-	return nil
-}
 
-// HasChangedOnDisk checks whether Config has changed on disk
-func (m *Manager) hasChangedOnDisk() bool {
-	nextFileInfo, err := os.Stat(m.fileInfo.Name())
+	plaintext, err := m.decode(raw, true)
 	if err != nil {
-		return false
+		return fmt.Errorf("Unable to decode backup #%d: %v", n, err)
 	}
-	hasChanged := nextFileInfo.Size() != m.fileInfo.Size() || nextFileInfo.ModTime() != m.fileInfo.ModTime()
-	return hasChanged
+
+	cfg := m.EmptyConfig()
+	if err := yaml.Unmarshal(plaintext, cfg); err != nil {
+		return fmt.Errorf("Unable to unmarshal backup #%d: %v", n, err)
+	}
+
+	if err := m.writeToDisk(cfg); err != nil {
+		return fmt.Errorf("Unable to restore backup #%d to disk: %v", n, err)
+	}
+
+	m.setCfg(cfg)
+	return nil
 }
 
 func (m *Manager) obfuscationStream() (cipher.Stream, error) {
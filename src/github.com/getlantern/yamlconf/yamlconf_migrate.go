@@ -0,0 +1,75 @@
+package yamlconf
+
+import "fmt"
+
+// migrate applies m.Migrations in order, walking cfg forward one version at
+// a time until it reaches the version that m.EmptyConfig() reports, or
+// returning an error if a required migration isn't registered. It returns
+// the migrated config and the source versions it migrated away from, in
+// order applied.
+func (m *Manager) migrate(cfg Config) (Config, []int, error) {
+	target := m.EmptyConfig().GetVersion()
+	var applied []int
+	for cfg.GetVersion() < target {
+		from := cfg.GetVersion()
+		migrate, ok := m.Migrations[from]
+		if !ok {
+			return cfg, applied, fmt.Errorf("No migration registered to upgrade config from version %d", from)
+		}
+
+		next, err := migrate(cfg)
+		if err != nil {
+			return cfg, applied, fmt.Errorf("Migration from version %d failed: %v", from, err)
+		}
+		if next.GetVersion() != from+1 {
+			return cfg, applied, fmt.Errorf("Migration from version %d produced version %d, expected %d", from, next.GetVersion(), from+1)
+		}
+
+		cfg = next
+		applied = append(applied, from)
+	}
+	return cfg, applied, nil
+}
+
+// PendingMigrations reports which source versions would be migrated if
+// Upgrade were called right now, without writing anything to disk or
+// updating the in-memory config. Operators can use this to see what a
+// deploy will do before it does it. It shares its walk with migrate and
+// Upgrade so the two can't drift apart.
+func (m *Manager) PendingMigrations() ([]int, error) {
+	cfg, err := m.doReadFromDisk(m.ObfuscationKey != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, pending, err := m.migrate(cfg)
+	return pending, err
+}
+
+// Upgrade runs any migrations needed to bring the on-disk config up to
+// EmptyConfig()'s version, writing the result back to disk through the
+// normal atomic-write path. Call it once at startup, before the config is
+// first loaded, so that
+// later code always sees a current-version config. It's a no-op if the
+// on-disk config is already current.
+func (m *Manager) Upgrade() error {
+	cfg, err := m.doReadFromDisk(m.ObfuscationKey != nil)
+	if err != nil {
+		return err
+	}
+
+	migrated, applied, err := m.migrate(cfg)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	log.Debugf("Applied %d migration(s) to config, now at version %d", len(applied), migrated.GetVersion())
+	if err := m.writeToDisk(migrated); err != nil {
+		return fmt.Errorf("Unable to write migrated config to disk: %v", err)
+	}
+	m.setCfg(migrated)
+	return nil
+}
@@ -0,0 +1,117 @@
+package yamlconf
+
+import (
+	"reflect"
+	"testing"
+)
+
+type migrateTestConfig struct {
+	Version int    `yaml:"version"`
+	Value   string `yaml:"value"`
+}
+
+func (c *migrateTestConfig) GetVersion() int  { return c.Version }
+func (c *migrateTestConfig) SetVersion(v int) { c.Version = v }
+func (c *migrateTestConfig) ApplyDefaults()   {}
+
+func bumpVersion(appendValue string) func(Config) (Config, error) {
+	return func(prev Config) (Config, error) {
+		p := prev.(*migrateTestConfig)
+		return &migrateTestConfig{Version: p.Version + 1, Value: p.Value + appendValue}, nil
+	}
+}
+
+func newMigrateTestManager() *Manager {
+	return &Manager{
+		FilePath:    "test.yaml",
+		Store:       NewMemStore("test.yaml"),
+		EmptyConfig: func() Config { return &migrateTestConfig{Version: 3} },
+		Migrations: map[int]func(Config) (Config, error){
+			0: bumpVersion("a"),
+			1: bumpVersion("b"),
+			2: bumpVersion("c"),
+		},
+	}
+}
+
+func TestPendingMigrationsMatchesMigrate(t *testing.T) {
+	m := newMigrateTestManager()
+	if err := m.Store.WriteAtomic([]byte("version: 0\nvalue: \"\"\n"), 0); err != nil {
+		t.Fatalf("seed WriteAtomic: %v", err)
+	}
+
+	pending, err := m.PendingMigrations()
+	if err != nil {
+		t.Fatalf("PendingMigrations: %v", err)
+	}
+	if !reflect.DeepEqual(pending, []int{0, 1, 2}) {
+		t.Fatalf("PendingMigrations = %v, want [0 1 2]", pending)
+	}
+}
+
+func TestPendingMigrationsReportsMissingMigration(t *testing.T) {
+	m := newMigrateTestManager()
+	delete(m.Migrations, 1)
+	if err := m.Store.WriteAtomic([]byte("version: 0\nvalue: \"\"\n"), 0); err != nil {
+		t.Fatalf("seed WriteAtomic: %v", err)
+	}
+
+	pending, err := m.PendingMigrations()
+	if err == nil {
+		t.Fatalf("expected PendingMigrations to fail when a migration is missing")
+	}
+	if !reflect.DeepEqual(pending, []int{0}) {
+		t.Fatalf("PendingMigrations = %v, want [0] (only the migrations that actually ran)", pending)
+	}
+}
+
+func TestUpgradeAppliesPendingMigrationsAndWritesBack(t *testing.T) {
+	m := newMigrateTestManager()
+	if err := m.Store.WriteAtomic([]byte("version: 0\nvalue: \"\"\n"), 0); err != nil {
+		t.Fatalf("seed WriteAtomic: %v", err)
+	}
+
+	if err := m.Upgrade(); err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	cfg := m.currentCfg().(*migrateTestConfig)
+	if cfg.Version != 3 || cfg.Value != "abc" {
+		t.Fatalf("currentCfg() = %+v, want Version 3, Value \"abc\"", cfg)
+	}
+
+	onDisk, err := m.doReadFromDisk(false)
+	if err != nil {
+		t.Fatalf("doReadFromDisk: %v", err)
+	}
+	if onDisk.GetVersion() != 3 {
+		t.Fatalf("on-disk version = %d, want 3", onDisk.GetVersion())
+	}
+}
+
+func TestUpgradeIsNoopWhenAlreadyCurrent(t *testing.T) {
+	m := newMigrateTestManager()
+	if err := m.Store.WriteAtomic([]byte("version: 3\nvalue: abc\n"), 3); err != nil {
+		t.Fatalf("seed WriteAtomic: %v", err)
+	}
+
+	before, err := m.Store.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	if err := m.Upgrade(); err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	after, err := m.Store.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if before.ModTime() != after.ModTime() {
+		t.Fatalf("Upgrade wrote to disk even though the config was already current")
+	}
+	if m.currentCfg() != nil {
+		t.Fatalf("Upgrade should not set an in-memory config when it's a no-op")
+	}
+}
@@ -0,0 +1,64 @@
+package yamlconf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// ConfigStore abstracts the persistence layer backing a Manager, so that
+// Manager itself never talks to os directly. When Manager.Store is nil,
+// Manager falls back to a ConfigStore built from Manager.FilePath (see
+// NewFileStore) for backwards compatibility.
+//
+// See NewFileStore, NewMemStore, NewHTTPStore and NewGitStore for the
+// implementations shipped with this package.
+type ConfigStore interface {
+	// Stat returns metadata about the current config, used to detect
+	// whether it has changed since the last Open.
+	Stat() (os.FileInfo, error)
+
+	// Open returns a reader over the current config contents. Callers must
+	// close it when done.
+	Open() (io.ReadCloser, error)
+
+	// WriteAtomic persists data as the new config contents, tagged with
+	// version for stores (like GitStore) that record history. Implementations
+	// that can should make the write atomic with respect to concurrent Opens
+	// (e.g. write-temp-then-rename).
+	WriteAtomic(data []byte, version int) error
+
+	// Watch notifies on the returned channel whenever the store's contents
+	// change, including as a result of a WriteAtomic call made through this
+	// same ConfigStore (callers that only care about external changes can
+	// compare against what they just wrote). It returns ErrWatchUnsupported
+	// if the store has no way to detect changes other than polling Stat.
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// ErrWatchUnsupported is returned by ConfigStore.Watch implementations that
+// can't subscribe to external changes; callers should fall back to polling
+// Stat instead.
+var ErrWatchUnsupported = errors.New("this ConfigStore does not support Watch")
+
+// BackupStore is implemented by ConfigStores that keep historical backups of
+// what they write and can hand them back out again, such as the default
+// fileStore. Manager.Restore uses this to recover from a bad write.
+type BackupStore interface {
+	ConfigStore
+
+	// Restore returns the raw (still encoded) contents of the nth most
+	// recent backup, with n==1 being the most recent.
+	Restore(n int) ([]byte, error)
+}
+
+// store returns the ConfigStore that this Manager should read and write
+// through, building the default filesystem-backed one from FilePath if Store
+// hasn't been set explicitly.
+func (m *Manager) store() ConfigStore {
+	if m.Store != nil {
+		return m.Store
+	}
+	return NewFileStore(m.FilePath, m.BackupCount)
+}
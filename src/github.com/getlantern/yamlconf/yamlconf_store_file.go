@@ -0,0 +1,182 @@
+package yamlconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileStore is the default ConfigStore, backed by a single file on the local
+// filesystem. Writes go to a sibling temp file that's fsync'd and then
+// renamed over the target, so a crash mid-write can never leave a
+// half-written or unreadable config behind. If BackupCount is positive, the
+// previous contents are rotated into a bounded ring of "<path>.bak.N" files
+// before the rename, so a bad write can be undone with Restore.
+type fileStore struct {
+	path        string
+	BackupCount int
+}
+
+// NewFileStore creates a ConfigStore backed by the file at path, keeping up
+// to backupCount rotating backups of prior versions. A backupCount of 0
+// disables backups.
+func NewFileStore(path string, backupCount int) ConfigStore {
+	return &fileStore{path: path, BackupCount: backupCount}
+}
+
+func (s *fileStore) Stat() (os.FileInfo, error) {
+	return os.Stat(s.path)
+}
+
+func (s *fileStore) Open() (io.ReadCloser, error) {
+	return os.Open(s.path)
+}
+
+func (s *fileStore) WriteAtomic(data []byte, version int) error {
+	dir := filepath.Dir(s.path)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to create temp file in %s: %v", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Unable to write temp file %s: %v", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Unable to fsync temp file %s: %v", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Unable to close temp file %s: %v", tmpPath, err)
+	}
+
+	if s.BackupCount > 0 {
+		if err := s.rotateBackups(); err != nil {
+			log.Errorf("Unable to rotate backups of %s, continuing without them: %v", s.path, err)
+		}
+	}
+
+	if err := renameOverwrite(tmpPath, s.path); err != nil {
+		return fmt.Errorf("Unable to rename %s to %s: %v", tmpPath, s.path, err)
+	}
+
+	return nil
+}
+
+// rotateBackups shifts the existing ring of backups down by one slot and
+// copies the current (pre-write) file into slot 1, discarding whatever was
+// in the oldest slot.
+func (s *fileStore) rotateBackups() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	os.Remove(s.backupPath(s.BackupCount))
+	for n := s.BackupCount - 1; n >= 1; n-- {
+		from, to := s.backupPath(n), s.backupPath(n+1)
+		if _, err := os.Stat(from); err == nil {
+			if err := renameOverwrite(from, to); err != nil {
+				return fmt.Errorf("Unable to rotate %s to %s: %v", from, to, err)
+			}
+		}
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s for backup: %v", s.path, err)
+	}
+	return ioutil.WriteFile(s.backupPath(1), data, 0644)
+}
+
+func (s *fileStore) backupPath(n int) string {
+	return fmt.Sprintf("%s.bak.%d", s.path, n)
+}
+
+// Restore returns the contents of the nth most recent backup (1 being the
+// most recent), implementing BackupStore.
+func (s *fileStore) Restore(n int) ([]byte, error) {
+	if n < 1 || n > s.BackupCount {
+		return nil, fmt.Errorf("Backup %d out of range, BackupCount is %d", n, s.BackupCount)
+	}
+	data, err := ioutil.ReadFile(s.backupPath(n))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read backup %s: %v", s.backupPath(n), err)
+	}
+	return data, nil
+}
+
+// Watch uses fsnotify to subscribe to changes to s.path. It watches the
+// parent directory rather than the file itself so that the atomic
+// write-temp-then-rename in WriteAtomic (and edits made by other processes
+// the same way) are still seen even though the original file's inode is
+// replaced rather than modified in place.
+func (s *fileStore) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create fsnotify watcher: %v", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("Unable to watch %s: %v", dir, err)
+	}
+
+	name := filepath.Base(s.path)
+	signals := make(chan struct{})
+	go func() {
+		defer watcher.Close()
+		defer close(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != name {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				select {
+				case signals <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("fsnotify error watching %s: %v", dir, err)
+			}
+		}
+	}()
+
+	return signals, nil
+}
+
+// renameOverwrite renames oldpath to newpath, overwriting newpath if it
+// exists. os.Rename already does this on POSIX systems; older Windows
+// releases instead fail with "file exists", so fall back to removing the
+// destination first.
+func renameOverwrite(oldpath, newpath string) error {
+	err := os.Rename(oldpath, newpath)
+	if err != nil && runtime.GOOS == "windows" {
+		if rmErr := os.Remove(newpath); rmErr == nil {
+			err = os.Rename(oldpath, newpath)
+		}
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package yamlconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreWriteAtomicAndBackupRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yamlconf-filestore")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	store := NewFileStore(path, 2)
+
+	versions := []string{"version: 1\n", "version: 2\n", "version: 3\n"}
+	for i, v := range versions {
+		if err := store.WriteAtomic([]byte(v), i+1); err != nil {
+			t.Fatalf("WriteAtomic #%d: %v", i+1, err)
+		}
+	}
+
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(current) != versions[2] {
+		t.Fatalf("expected %s to hold the latest version, got %q", path, current)
+	}
+
+	bs, ok := store.(BackupStore)
+	if !ok {
+		t.Fatalf("fileStore must implement BackupStore")
+	}
+
+	backup1, err := bs.Restore(1)
+	if err != nil {
+		t.Fatalf("Restore(1): %v", err)
+	}
+	if string(backup1) != versions[1] {
+		t.Fatalf("Restore(1) = %q, want %q (the version just before the latest write)", backup1, versions[1])
+	}
+
+	backup2, err := bs.Restore(2)
+	if err != nil {
+		t.Fatalf("Restore(2): %v", err)
+	}
+	if string(backup2) != versions[0] {
+		t.Fatalf("Restore(2) = %q, want %q", backup2, versions[0])
+	}
+
+	if _, err := bs.Restore(3); err == nil {
+		t.Fatalf("expected Restore(3) to fail, BackupCount is 2")
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(leftovers) != 0 {
+		t.Fatalf("expected no leftover temp files after WriteAtomic, found %v", leftovers)
+	}
+}
+
+func TestFileStoreRestoreFailsWithoutBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yamlconf-filestore")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	store := NewFileStore(path, 0)
+
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+	if err := store.WriteAtomic([]byte("version: 2\n"), 2); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+
+	bs, ok := store.(BackupStore)
+	if !ok {
+		t.Fatalf("fileStore must implement BackupStore")
+	}
+	if _, err := bs.Restore(1); err == nil {
+		t.Fatalf("expected Restore to fail when BackupCount is 0")
+	}
+}
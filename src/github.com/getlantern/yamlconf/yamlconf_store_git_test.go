@@ -0,0 +1,111 @@
+package yamlconf
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "yamlconf-gitstore")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "Test")
+
+	return dir
+}
+
+func TestGitStoreWriteAtomicCommitsEachVersion(t *testing.T) {
+	dir := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	store := NewGitStore(dir, "config.yaml")
+
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic #1: %v", err)
+	}
+	if err := store.WriteAtomic([]byte("version: 2\n"), 2); err != nil {
+		t.Fatalf("WriteAtomic #2: %v", err)
+	}
+
+	current, err := ioutil.ReadFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(current) != "version: 2\n" {
+		t.Fatalf("working tree = %q, want %q", current, "version: 2\n")
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "--oneline").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log: %v: %s", err, out)
+	}
+	if got := len(strings.Split(strings.TrimSpace(string(out)), "\n")); got != 2 {
+		t.Fatalf("git log has %d commit(s), want 2: %s", got, out)
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(leftovers) != 0 {
+		t.Fatalf("expected no leftover temp files after WriteAtomic, found %v", leftovers)
+	}
+}
+
+func TestGitStoreWriteAtomicNoopWhenContentUnchanged(t *testing.T) {
+	dir := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	store := NewGitStore(dir, "config.yaml")
+
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic #1: %v", err)
+	}
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic #2 (identical content) should not error: %v", err)
+	}
+}
+
+func TestGitStoreStatAndOpen(t *testing.T) {
+	dir := newTestGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	store := NewGitStore(dir, "config.yaml")
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+
+	if _, err := store.Stat(); err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	r, err := store.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	buf := make([]byte, 64)
+	n, _ := r.Read(buf)
+	if string(buf[:n]) != "version: 1\n" {
+		t.Fatalf("Open() contents = %q, want %q", buf[:n], "version: 1\n")
+	}
+}
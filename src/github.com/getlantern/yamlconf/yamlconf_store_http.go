@@ -0,0 +1,66 @@
+package yamlconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// HTTPStore is a read-only ConfigStore that fetches the config from a
+// centrally hosted URL, for deployments that distribute configuration from a
+// config server rather than editing files on each host.
+type HTTPStore struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPStore creates a read-only ConfigStore that fetches url with
+// http.DefaultClient.
+func NewHTTPStore(url string) *HTTPStore {
+	return &HTTPStore{URL: url}
+}
+
+func (s *HTTPStore) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// Stat issues a HEAD request so that reloadFromDisk can tell whether the
+// remote config changed without fetching the full body.
+func (s *HTTPStore) Stat() (os.FileInfo, error) {
+	resp, err := s.client().Head(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to HEAD %s: %v", s.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status %d HEADing %s", resp.StatusCode, s.URL)
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return memFileInfo{name: s.URL, size: resp.ContentLength, modTime: modTime}, nil
+}
+
+func (s *HTTPStore) Open() (io.ReadCloser, error) {
+	resp, err := s.client().Get(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to GET %s: %v", s.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Unexpected status %d GETing %s", resp.StatusCode, s.URL)
+	}
+	return resp.Body, nil
+}
+
+func (s *HTTPStore) WriteAtomic(data []byte, version int) error {
+	return errors.New("HTTPStore is read-only")
+}
+
+func (s *HTTPStore) Watch(ctx context.Context) (<-chan struct{}, error) {
+	return nil, ErrWatchUnsupported
+}
@@ -0,0 +1,70 @@
+package yamlconf
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPStoreStatAndOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("version: 1\n"))
+	}))
+	defer server.Close()
+
+	store := NewHTTPStore(server.URL)
+
+	fileInfo, err := store.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fileInfo.Size() != int64(len("version: 1\n")) {
+		t.Fatalf("Stat().Size() = %d, want %d", fileInfo.Size(), len("version: 1\n"))
+	}
+
+	r, err := store.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(body) != "version: 1\n" {
+		t.Fatalf("Open() contents = %q, want %q", body, "version: 1\n")
+	}
+}
+
+func TestHTTPStoreStatAndOpenSurfaceNonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	store := NewHTTPStore(server.URL)
+
+	if _, err := store.Stat(); err == nil {
+		t.Fatalf("expected Stat to fail against a 404")
+	}
+	if _, err := store.Open(); err == nil {
+		t.Fatalf("expected Open to fail against a 404")
+	}
+}
+
+func TestHTTPStoreIsReadOnly(t *testing.T) {
+	store := NewHTTPStore("http://example.invalid/config.yaml")
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err == nil {
+		t.Fatalf("expected WriteAtomic to fail, HTTPStore is read-only")
+	}
+}
+
+func TestHTTPStoreWatchUnsupported(t *testing.T) {
+	store := NewHTTPStore("http://example.invalid/config.yaml")
+	if _, err := store.Watch(context.Background()); err != ErrWatchUnsupported {
+		t.Fatalf("Watch() = %v, want ErrWatchUnsupported", err)
+	}
+}
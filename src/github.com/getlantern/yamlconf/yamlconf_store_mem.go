@@ -0,0 +1,72 @@
+package yamlconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// MemStore is an in-memory ConfigStore. It's mainly useful in tests that
+// want to exercise Manager without touching the filesystem.
+type MemStore struct {
+	name string
+
+	mu      sync.Mutex
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemStore creates an in-memory ConfigStore. name is cosmetic; it's only
+// used to populate the Name() of the os.FileInfo returned from Stat.
+func NewMemStore(name string) *MemStore {
+	return &MemStore{name: name}
+}
+
+func (s *MemStore) Stat() (os.FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return nil, os.ErrNotExist
+	}
+	return memFileInfo{name: s.name, size: int64(len(s.data)), modTime: s.modTime}, nil
+}
+
+func (s *MemStore) Open() (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.data)), nil
+}
+
+func (s *MemStore) WriteAtomic(data []byte, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append([]byte(nil), data...)
+	s.modTime = time.Now()
+	return nil
+}
+
+func (s *MemStore) Watch(ctx context.Context) (<-chan struct{}, error) {
+	return nil, ErrWatchUnsupported
+}
+
+// memFileInfo is a minimal os.FileInfo for stores that don't have a real
+// file backing them.
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() interface{}   { return nil }
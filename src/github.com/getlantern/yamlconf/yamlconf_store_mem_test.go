@@ -0,0 +1,56 @@
+package yamlconf
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestMemStoreStatAndOpenBeforeFirstWrite(t *testing.T) {
+	store := NewMemStore("test.yaml")
+
+	if _, err := store.Stat(); err != os.ErrNotExist {
+		t.Fatalf("Stat before first write = %v, want os.ErrNotExist", err)
+	}
+	if _, err := store.Open(); err != os.ErrNotExist {
+		t.Fatalf("Open before first write = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestMemStoreWriteAtomicThenReadBack(t *testing.T) {
+	store := NewMemStore("test.yaml")
+
+	if err := store.WriteAtomic([]byte("version: 1\n"), 1); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+
+	fileInfo, err := store.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fileInfo.Name() != "test.yaml" {
+		t.Fatalf("Stat().Name() = %q, want %q", fileInfo.Name(), "test.yaml")
+	}
+	if fileInfo.Size() != int64(len("version: 1\n")) {
+		t.Fatalf("Stat().Size() = %d, want %d", fileInfo.Size(), len("version: 1\n"))
+	}
+
+	r, err := store.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	buf := make([]byte, 64)
+	n, _ := r.Read(buf)
+	if string(buf[:n]) != "version: 1\n" {
+		t.Fatalf("Open() contents = %q, want %q", buf[:n], "version: 1\n")
+	}
+}
+
+func TestMemStoreWatchUnsupported(t *testing.T) {
+	store := NewMemStore("test.yaml")
+	if _, err := store.Watch(context.Background()); err != ErrWatchUnsupported {
+		t.Fatalf("Watch() = %v, want ErrWatchUnsupported", err)
+	}
+}
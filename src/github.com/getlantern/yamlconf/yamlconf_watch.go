@@ -0,0 +1,123 @@
+package yamlconf
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"time"
+)
+
+// ConfigChangeEvent describes a change to a Manager's config picked up by
+// Watch.
+type ConfigChangeEvent struct {
+	Old Config
+	New Config
+}
+
+// watchDebounce is how long Watch waits after the last change notification
+// in a burst before reloading. This collapses bursts of notifications, such
+// as multi-write saves from editors, into a single reload.
+const watchDebounce = 250 * time.Millisecond
+
+// watchPollInterval is how often Watch falls back to polling Stat when the
+// Manager's ConfigStore doesn't support Watch, e.g. on some network
+// filesystems.
+const watchPollInterval = 1 * time.Second
+
+// Watch subscribes to changes to the Manager's config and returns a channel
+// of ConfigChangeEvent, one per settled burst of changes, reloading exactly
+// once per burst. If the underlying ConfigStore can't be watched, Watch
+// transparently falls back to polling Stat every watchPollInterval. The
+// returned channel is closed once ctx is done.
+func (m *Manager) Watch(ctx context.Context) (<-chan ConfigChangeEvent, error) {
+	signals, err := m.store().Watch(ctx)
+	polling := err == ErrWatchUnsupported
+	if err != nil && !polling {
+		return nil, err
+	}
+	if polling {
+		log.Debug("ConfigStore does not support Watch, falling back to polling")
+	}
+
+	// last and lastFileInfo are captured here, synchronously, rather than
+	// inside watchLoop's goroutine: they seed the state watchLoop diffs
+	// against, and doing it before that goroutine is even scheduled closes
+	// a window where a write landing between Watch returning and the
+	// goroutine's first line running would otherwise be missed entirely.
+	last := m.currentCfg()
+	lastFileInfo := m.currentFileInfo()
+
+	events := make(chan ConfigChangeEvent)
+	go m.watchLoop(ctx, signals, polling, last, lastFileInfo, events)
+	return events, nil
+}
+
+// watchLoop reloads and notifies events on every settled burst of changes,
+// diffing against last/lastFileInfo rather than m.cfg/m.currentFileInfo():
+// reloadFromDisk's own "changed" signal (and a Stat-based polling check
+// using m.currentFileInfo()) both compare against Manager state that a
+// write this same Manager made (via saveToDiskAndUpdate, Restore, or
+// Upgrade) already updates before this loop ever sees it, so either
+// comparison always comes back "unchanged" for self-writes. A subscriber
+// has no way to tell "this process changed the file" from "something else
+// did" and doesn't care; comparing against state private to this loop
+// instead catches both.
+func (m *Manager) watchLoop(ctx context.Context, signals <-chan struct{}, polling bool, last Config, lastFileInfo os.FileInfo, events chan<- ConfigChangeEvent) {
+	defer close(events)
+
+	var poll <-chan time.Time
+	if polling {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case _, ok := <-signals:
+			if !ok {
+				return
+			}
+			debounce = time.After(watchDebounce)
+
+		case <-poll:
+			nextFileInfo, err := m.store().Stat()
+			if err == nil && fileInfoChanged(lastFileInfo, nextFileInfo) {
+				lastFileInfo = nextFileInfo
+				debounce = time.After(watchDebounce)
+			}
+
+		case <-debounce:
+			debounce = nil
+			if _, err := m.reloadFromDisk(); err != nil {
+				log.Errorf("Error reloading config after change notification: %v", err)
+				continue
+			}
+			next := m.currentCfg()
+			if reflect.DeepEqual(last, next) {
+				continue
+			}
+			old := last
+			last = next
+			select {
+			case events <- ConfigChangeEvent{Old: old, New: next}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// fileInfoChanged reports whether nextFileInfo differs from fileInfo in size
+// or modtime. fileInfo may be nil, meaning nothing has been observed yet, in
+// which case any nextFileInfo counts as a change.
+func fileInfoChanged(fileInfo, nextFileInfo os.FileInfo) bool {
+	if fileInfo == nil {
+		return true
+	}
+	return nextFileInfo.Size() != fileInfo.Size() || nextFileInfo.ModTime() != fileInfo.ModTime()
+}
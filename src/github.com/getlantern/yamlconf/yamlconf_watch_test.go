@@ -0,0 +1,121 @@
+package yamlconf
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const watchTestTimeout = 3 * time.Second
+
+func newWatchTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		FilePath:    "test.yaml",
+		Store:       NewMemStore("test.yaml"),
+		EmptyConfig: func() Config { return &migrateTestConfig{} },
+	}
+	if err := m.Store.WriteAtomic([]byte("version: 0\nvalue: \"\"\n"), 0); err != nil {
+		t.Fatalf("seed WriteAtomic: %v", err)
+	}
+	if err := m.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk: %v", err)
+	}
+	return m
+}
+
+func awaitEvent(t *testing.T, events <-chan ConfigChangeEvent) ConfigChangeEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatalf("events channel closed before a change event arrived")
+		}
+		return ev
+	case <-time.After(watchTestTimeout):
+		t.Fatalf("timed out waiting for a change event")
+		return ConfigChangeEvent{}
+	}
+}
+
+// TestWatchEmitsEventForSelfWrite guards against Watch silently dropping a
+// notification for a change the same Manager made via saveToDiskAndUpdate:
+// since that path already updates m.cfg before the polling goroutine's
+// debounced reload runs, comparing the reload against m.cfg would always see
+// "unchanged" for this case.
+func TestWatchEmitsEventForSelfWrite(t *testing.T) {
+	m := newWatchTestManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := m.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	updated := &migrateTestConfig{Version: m.currentCfg().GetVersion(), Value: "updated"}
+	changed, err := m.saveToDiskAndUpdate(updated)
+	if err != nil {
+		t.Fatalf("saveToDiskAndUpdate: %v", err)
+	}
+	if !changed {
+		t.Fatalf("saveToDiskAndUpdate reported no change")
+	}
+
+	ev := awaitEvent(t, events)
+	newCfg := ev.New.(*migrateTestConfig)
+	if newCfg.Value != "updated" {
+		t.Fatalf("event.New.Value = %q, want %q", newCfg.Value, "updated")
+	}
+	oldCfg := ev.Old.(*migrateTestConfig)
+	if oldCfg.Value != "" {
+		t.Fatalf("event.Old.Value = %q, want %q", oldCfg.Value, "")
+	}
+}
+
+// TestWatchEmitsEventForExternalWrite covers the non-self case: some other
+// component writes to the same ConfigStore directly, bypassing this Manager
+// entirely.
+func TestWatchEmitsEventForExternalWrite(t *testing.T) {
+	m := newWatchTestManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := m.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	if err := m.Store.WriteAtomic([]byte("version: 0\nvalue: external\n"), 0); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+
+	ev := awaitEvent(t, events)
+	newCfg := ev.New.(*migrateTestConfig)
+	if newCfg.Value != "external" {
+		t.Fatalf("event.New.Value = %q, want %q", newCfg.Value, "external")
+	}
+}
+
+func TestWatchClosesEventsChannelWhenContextDone(t *testing.T) {
+	m := newWatchTestManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := m.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatalf("expected events channel to be closed after ctx cancellation")
+		}
+	case <-time.After(watchTestTimeout):
+		t.Fatalf("timed out waiting for events channel to close")
+	}
+}